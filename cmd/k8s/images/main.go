@@ -2,20 +2,70 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 	"slices"
+	"strings"
+	"time"
 
 	"github.com/julien-fruteau/go-kubernetes/external/k8s"
+	"github.com/julien-fruteau/go-kubernetes/external/registry"
+	"github.com/julien-fruteau/go-kubernetes/internal/format"
 )
 
 func main() {
-	output := flag.String("output", "json", "output format: json or raw")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "gc":
+			runGC(os.Args[2:])
+			return
+		case "watch":
+			runWatch(os.Args[2:])
+			return
+		}
+	}
+
+	output := flag.String("output", "json", "output format: json, yaml, table, or template")
+	tmpl := flag.String("template", "", "go-template string, required when --output=template")
+	contexts := flag.String("contexts", "", "comma-separated kubeconfig contexts to aggregate images from (default: current context only, or in-cluster)")
+	all := flag.Bool("all", false, "include images only present on workload controllers' pod templates, plus pull-secret and namespace columns")
 	flag.Parse()
 
-	k, err := k8s.NewK8SOutCli(context.Background())
+	ctx := context.Background()
+
+	if *all {
+		var refs []k8s.ImageReference
+		var err error
+		if *contexts != "" {
+			refs, err = multiClusterImageRefs(ctx, *contexts)
+		} else {
+			var k *k8s.K8SOutCli
+			k, err = k8s.NewK8SClient(ctx, k8s.ClientOptions{})
+			if err == nil {
+				refs, err = k.GetClusterImagesAll(ctx)
+			}
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		printImageRefs(refs, *output, *tmpl)
+		return
+	}
+
+	if *contexts != "" {
+		images, err := multiClusterImages(*contexts)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		slices.Sort(images)
+		printImages(images, *output, *tmpl)
+		return
+	}
+
+	k, err := k8s.NewK8SClient(ctx, k8s.ClientOptions{})
 	if err != nil {
 		panic(err)
 	}
@@ -23,22 +73,203 @@ func main() {
 	images, err := k.GetClusterImagesV1()
 	// images, err := k.GetClusterImagesV2()
 	if err != nil {
-    fmt.Println(err)
+		fmt.Println(err)
 		os.Exit(1)
 	}
 	slices.Sort(images)
+	printImages(images, *output, *tmpl)
+}
 
-	// fmt.Println(images)
-	switch *output {
-	case "json":
-		jsonData, err := json.Marshal(images)
-		if err != nil {
-			fmt.Println("Error:", err)
-			return
+// multiClusterImages aggregates deduped images across every listed
+// kubeconfig context, reporting per-cluster failures without aborting
+// the whole run.
+func multiClusterImages(contexts string) ([]string, error) {
+	m, err := k8s.NewK8SMultiCli(context.Background(), splitCSV(contexts))
+	if err != nil {
+		return nil, err
+	}
+
+	images, errs := m.GetClusterImagesV1()
+	for _, e := range errs {
+		fmt.Fprintln(os.Stderr, "warning:", e.Error())
+	}
+	return images, nil
+}
+
+// multiClusterImageRefs aggregates deduped ImageReferences across every
+// listed kubeconfig context, reporting per-cluster failures without
+// aborting the whole run.
+func multiClusterImageRefs(ctx context.Context, contexts string) ([]k8s.ImageReference, error) {
+	m, err := k8s.NewK8SMultiCli(ctx, splitCSV(contexts))
+	if err != nil {
+		return nil, err
+	}
+
+	refs, errs := m.GetClusterImagesAll(ctx)
+	for _, e := range errs {
+		fmt.Fprintln(os.Stderr, "warning:", e.Error())
+	}
+	return refs, nil
+}
+
+func printImages(images []string, output, tmpl string) {
+	formatter, err := format.New(output, tmpl)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	entries := format.EntriesFromImages(images)
+	format.SortEntries(entries)
+
+	if err := formatter.Format(os.Stdout, entries); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// printImageRefs is printImages for the richer k8s.ImageReference data
+// GetClusterImagesAll produces, so NAMESPACES/PULLSECRETS are populated.
+func printImageRefs(refs []k8s.ImageReference, output, tmpl string) {
+	formatter, err := format.New(output, tmpl)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	entries := format.EntriesFromImageReferences(refs)
+	format.SortEntries(entries)
+
+	if err := formatter.Format(os.Stdout, entries); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// runGC prunes a registry of images no longer referenced by the cluster.
+func runGC(args []string) {
+	fs := flag.NewFlagSet("gc", flag.ExitOnError)
+	registryURL := fs.String("registry", "", "registry base URL, e.g. https://registry.example.com")
+	bearerToken := fs.String("token", "", "bearer token for registry auth")
+	username := fs.String("username", "", "username for registry basic auth")
+	password := fs.String("password", "", "password for registry basic auth")
+	dryRun := fs.Bool("dry-run", true, "only print what would be deleted")
+	keepLast := fs.Int("keep-last", 1, "always keep the N most recently created tags per repository")
+	keepNewerThan := fs.Duration("keep-newer-than", 0, "always keep tags created more recently than this duration")
+	allow := fs.String("allow", "", "comma-separated list of repositories to prune (default: all)")
+	deny := fs.String("deny", "", "comma-separated list of repositories to never prune")
+	contexts := fs.String("contexts", "", "comma-separated kubeconfig contexts to aggregate referenced images from, for a registry shared by multiple clusters (default: current context only, or in-cluster)")
+	fs.Parse(args)
+
+	if *registryURL == "" {
+		fmt.Println("gc: -registry is required")
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+
+	// GetClusterImagesAll (rather than GetClusterImagesV1) so the
+	// referenced set also covers images only present on workload
+	// controllers' pod templates, not just currently-running pods.
+	// --contexts fans this out across every listed cluster, so one gc run
+	// can prune a registry shared by multiple clusters.
+	var refs []k8s.ImageReference
+	var err error
+	if *contexts != "" {
+		refs, err = multiClusterImageRefs(ctx, *contexts)
+	} else {
+		var k *k8s.K8SOutCli
+		k, err = k8s.NewK8SClient(ctx, k8s.ClientOptions{})
+		if err == nil {
+			refs, err = k.GetClusterImagesAll(ctx)
 		}
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	referenced := make(map[string]struct{}, len(refs))
+	for _, ref := range refs {
+		referenced[ref.Image] = struct{}{}
+	}
 
-		fmt.Fprintln(os.Stdout, string(jsonData))
-	case "raw":
-		fmt.Println(images)
+	c := registry.NewClient(registry.Config{
+		BaseURL:       *registryURL,
+		BearerToken:   *bearerToken,
+		Username:      *username,
+		Password:      *password,
+		DryRun:        *dryRun,
+		KeepLast:      *keepLast,
+		KeepNewerThan: *keepNewerThan,
+		Allow:         splitCSV(*allow),
+		Deny:          splitCSV(*deny),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	plan, err := c.Plan(ctx, referenced)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if err := c.Execute(ctx, plan); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// runWatch keeps an ImageIndex running and prints the referenced-image
+// snapshot plus every Add/Remove event as they happen, instead of
+// re-listing the cluster on every invocation.
+func runWatch(args []string) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	resync := fs.Duration("resync", 10*time.Minute, "informer resync period")
+	fs.Parse(args)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	k, err := k8s.NewK8SClient(ctx, k8s.ClientOptions{})
+	if err != nil {
+		panic(err)
+	}
+
+	idx := k8s.NewImageIndex(k.Clientset(), *resync)
+	events := idx.Subscribe()
+	idx.Start(ctx)
+
+	if err := idx.WaitForSync(ctx); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	images := idx.Snapshot()
+	slices.Sort(images)
+	fmt.Println("synced, currently referenced images:")
+	for _, image := range images {
+		fmt.Println(" ", image)
+	}
+
+	for event := range events {
+		verb := "added"
+		if event.Type == k8s.ImageRemoved {
+			verb = "removed"
+		}
+		fmt.Printf("%s: %s\n", verb, event.Image)
+	}
+}
+
+func splitCSV(s string) []string {
+	if s == "" {
+		return nil
+	}
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part != "" {
+			out = append(out, part)
+		}
 	}
+	return out
 }
@@ -0,0 +1,232 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/julien-fruteau/go-kubernetes/internal/env"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Instanciate a new kubernetes in-cluster client
+// using the service account token and API server mounted into the pod
+// (KUBERNETES_SERVICE_HOST / KUBERNETES_SERVICE_PORT env vars).
+func NewK8SInCli(ctx context.Context) (*K8SOutCli, error) {
+	k := &K8SOutCli{}
+
+	if ctx != nil {
+		k.ctx = ctx
+	} else {
+		k.ctx = context.Background()
+	}
+
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	k.clientset, err = kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// ClientMode selects how NewK8SClient picks between in-cluster and
+// out-of-cluster configuration.
+type ClientMode int
+
+const (
+	ClientModeAuto ClientMode = iota
+	ClientModeInCluster
+	ClientModeOutOfCluster
+)
+
+// ClientOptions configures NewK8SClient.
+type ClientOptions struct {
+	// Mode forces in-cluster or out-of-cluster construction. Defaults to
+	// ClientModeAuto: in-cluster when KUBERNETES_SERVICE_HOST is set,
+	// out-of-cluster (kubeconfig) otherwise.
+	Mode ClientMode
+}
+
+// NewK8SClient builds a client, auto-detecting whether it is running
+// inside or outside the cluster unless overridden via opts.Mode.
+func NewK8SClient(ctx context.Context, opts ClientOptions) (*K8SOutCli, error) {
+	mode := opts.Mode
+	if mode == ClientModeAuto {
+		if env.GetEnvOrDefault("KUBERNETES_SERVICE_HOST", "") != "" {
+			mode = ClientModeInCluster
+		} else {
+			mode = ClientModeOutOfCluster
+		}
+	}
+
+	if mode == ClientModeInCluster {
+		return NewK8SInCli(ctx)
+	}
+	return NewK8SOutCli(ctx)
+}
+
+// MultiClusterError reports a per-cluster failure from a K8SMultiCli
+// operation without failing the whole fan-out.
+type MultiClusterError struct {
+	Context string
+	Err     error
+}
+
+func (e *MultiClusterError) Error() string {
+	return fmt.Sprintf("context %s: %v", e.Context, e.Err)
+}
+
+// K8SMultiCli fans a client out across several kubeconfig contexts so
+// operations can aggregate results from multiple clusters in one pass.
+type K8SMultiCli struct {
+	ctx     context.Context
+	clients map[string]*K8SOutCli
+}
+
+// NewK8SMultiCli builds a K8SMultiCli for the given kubeconfig contexts,
+// or every context in the kubeconfig if contexts is empty.
+func NewK8SMultiCli(ctx context.Context, contexts []string) (*K8SMultiCli, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	kubeconfig, err := getKubeConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	rawConfig, err := clientcmd.LoadFromFile(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(contexts) == 0 {
+		for name := range rawConfig.Contexts {
+			contexts = append(contexts, name)
+		}
+	}
+
+	clients := make(map[string]*K8SOutCli, len(contexts))
+	for _, name := range contexts {
+		config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfig},
+			&clientcmd.ConfigOverrides{CurrentContext: name},
+		).ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("context %s: %w", name, err)
+		}
+
+		clientset, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			return nil, fmt.Errorf("context %s: %w", name, err)
+		}
+
+		clients[name] = &K8SOutCli{ctx: ctx, clientset: clientset}
+	}
+
+	return &K8SMultiCli{ctx: ctx, clients: clients}, nil
+}
+
+// GetClusterImagesV1 aggregates the deduped images across every configured
+// cluster, running one cluster per goroutine. A failure in one cluster is
+// reported in the returned errs slice rather than failing the others.
+func (m *K8SMultiCli) GetClusterImagesV1() (images []string, errs []MultiClusterError) {
+	type result struct {
+		context string
+		images  []string
+		err     error
+	}
+
+	results := make(chan result, len(m.clients))
+	var wg sync.WaitGroup
+	for name, client := range m.clients {
+		wg.Add(1)
+		go func(name string, client *K8SOutCli) {
+			defer wg.Done()
+			clusterImages, err := client.GetClusterImagesV1()
+			results <- result{context: name, images: clusterImages, err: err}
+		}(name, client)
+	}
+
+	wg.Wait()
+	close(results)
+
+	imageSet := make(map[string]struct{})
+	for r := range results {
+		if r.err != nil {
+			errs = append(errs, MultiClusterError{Context: r.context, Err: r.err})
+			continue
+		}
+		for _, image := range r.images {
+			imageSet[image] = struct{}{}
+		}
+	}
+
+	images = make([]string, 0, len(imageSet))
+	for image := range imageSet {
+		images = append(images, image)
+	}
+	return images, errs
+}
+
+// GetClusterImagesAll aggregates the deduped ImageReferences across every
+// configured cluster, running one cluster per goroutine. A failure in one
+// cluster is reported in the returned errs slice rather than failing the
+// others. References to the same image from different clusters are merged,
+// unioning their Sources and PullSecrets.
+func (m *K8SMultiCli) GetClusterImagesAll(ctx context.Context) (refs []ImageReference, errs []MultiClusterError) {
+	type result struct {
+		context string
+		refs    []ImageReference
+		err     error
+	}
+
+	results := make(chan result, len(m.clients))
+	var wg sync.WaitGroup
+	for name, client := range m.clients {
+		wg.Add(1)
+		go func(name string, client *K8SOutCli) {
+			defer wg.Done()
+			clusterRefs, err := client.GetClusterImagesAll(ctx)
+			results <- result{context: name, refs: clusterRefs, err: err}
+		}(name, client)
+	}
+
+	wg.Wait()
+	close(results)
+
+	merged := make(map[string]*ImageReference)
+	for r := range results {
+		if r.err != nil {
+			errs = append(errs, MultiClusterError{Context: r.context, Err: r.err})
+			continue
+		}
+		for _, ref := range r.refs {
+			existing, ok := merged[ref.Image]
+			if !ok {
+				copied := ref
+				merged[ref.Image] = &copied
+				continue
+			}
+			for _, source := range ref.Sources {
+				existing.Sources = appendObjectRef(existing.Sources, source)
+			}
+			for _, secret := range ref.PullSecrets {
+				existing.PullSecrets = appendNamespacedName(existing.PullSecrets, secret)
+			}
+		}
+	}
+
+	refs = make([]ImageReference, 0, len(merged))
+	for _, ref := range merged {
+		refs = append(refs, *ref)
+	}
+	return refs, errs
+}
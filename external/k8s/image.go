@@ -1,23 +1,99 @@
 package k8s
 
-import "strings"
+import (
+	"errors"
+	"strings"
+)
 
+const (
+	defaultRegistry  = "docker.io"
+	defaultNamespace = "library"
+	defaultTag       = "latest"
+)
+
+var errEmptyReference = errors.New("k8s: empty image reference")
+
+// Image represents a fully-qualified OCI image reference, split into its
+// component parts: registry/namespace/repository:tag@digest
 type Image struct {
+	Registry   string `json:"registry"`
+	Namespace  string `json:"namespace"`
 	Repository string `json:"repository"`
-	Tag        string `json:"tag"`
+	Tag        string `json:"tag,omitempty"`
+	Digest     string `json:"digest,omitempty"`
 }
 
-// get image from registry/repository:tag
+// looksLikeRegistry reports whether s is the host part of a reference
+// (as opposed to the first path segment of a repository), following the
+// same heuristic as docker/distribution: a registry host contains a "."
+// or a ":" (port), or is exactly "localhost".
+func looksLikeRegistry(s string) bool {
+	return s == "localhost" || strings.ContainsAny(s, ".:")
+}
+
+// ParseReference parses an OCI/Docker image reference such as
+// "nginx", "nginx:1.25", "library/nginx@sha256:...",
+// "gcr.io/proj/img:tag@sha256:..." or "localhost:5000/x" into an Image.
+func ParseReference(s string) (*Image, error) {
+	if s == "" {
+		return nil, errEmptyReference
+	}
+
+	image := &Image{}
+
+	// split off the digest first, it's always the last "@" segment
+	name := s
+	if i := strings.LastIndex(s, "@"); i != -1 {
+		name = s[:i]
+		image.Digest = s[i+1:]
+	}
+
+	// split off the registry: the first "/"-separated segment is a
+	// registry host only if it contains a "." or ":" or is "localhost"
+	var registry string
+	rest := name
+	if i := strings.Index(name, "/"); i != -1 {
+		candidate := name[:i]
+		if looksLikeRegistry(candidate) {
+			registry = candidate
+			rest = name[i+1:]
+		}
+	}
+	if registry == "" {
+		registry = defaultRegistry
+	}
+	image.Registry = registry
+
+	// rest is namespace/.../repository:tag (tag belongs to the last segment)
+	repoPath := rest
+	tag := ""
+	if i := strings.LastIndex(rest, ":"); i != -1 {
+		repoPath = rest[:i]
+		tag = rest[i+1:]
+	}
+	if tag == "" && image.Digest == "" {
+		tag = defaultTag
+	}
+	image.Tag = tag
+
+	if i := strings.LastIndex(repoPath, "/"); i != -1 {
+		image.Namespace = repoPath[:i]
+		image.Repository = repoPath[i+1:]
+	} else {
+		image.Namespace = defaultNamespace
+		image.Repository = repoPath
+	}
+
+	return image, nil
+}
+
+// getFromImageName populates i from a registry/namespace/repository:tag@digest string.
 func (i *Image) getFromImageName(name string) {
-	s := strings.Split(name, ":")
-	if len(s) == 1 {
-		i.Repository = name
-		i.Tag = "latest"
+	parsed, err := ParseReference(name)
+	if err != nil {
 		return
 	}
-
-	i.Repository = s[0]
-	i.Tag = s[1]
+	*i = *parsed
 }
 
 func NewImage(s string) *Image {
@@ -25,3 +101,51 @@ func NewImage(s string) *Image {
 	image.getFromImageName(s)
 	return image
 }
+
+// Familiar returns the shortest form of the reference that still round-trips:
+// the registry/namespace are omitted when they are the Docker Hub defaults.
+func (i *Image) Familiar() string {
+	var b strings.Builder
+
+	if i.Registry != defaultRegistry {
+		b.WriteString(i.Registry)
+		b.WriteByte('/')
+	}
+	if i.Namespace != defaultNamespace || i.Registry != defaultRegistry {
+		b.WriteString(i.Namespace)
+		b.WriteByte('/')
+	}
+	b.WriteString(i.Repository)
+	if i.Tag != "" {
+		b.WriteByte(':')
+		b.WriteString(i.Tag)
+	}
+	if i.Digest != "" {
+		b.WriteByte('@')
+		b.WriteString(i.Digest)
+	}
+
+	return b.String()
+}
+
+// String returns the fully-qualified form of the reference, always
+// including registry and namespace.
+func (i *Image) String() string {
+	var b strings.Builder
+
+	b.WriteString(i.Registry)
+	b.WriteByte('/')
+	b.WriteString(i.Namespace)
+	b.WriteByte('/')
+	b.WriteString(i.Repository)
+	if i.Tag != "" {
+		b.WriteByte(':')
+		b.WriteString(i.Tag)
+	}
+	if i.Digest != "" {
+		b.WriteByte('@')
+		b.WriteString(i.Digest)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,261 @@
+package k8s
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ImageEventType distinguishes the two events an ImageIndex can emit.
+type ImageEventType int
+
+const (
+	ImageAdded ImageEventType = iota
+	ImageRemoved
+)
+
+// ImageEvent is emitted whenever an image transitions between referenced
+// and unreferenced in the cluster.
+type ImageEvent struct {
+	Type  ImageEventType
+	Image string
+}
+
+// ImageIndex watches Pods through a shared informer and maintains a
+// reference-counted, always-up-to-date set of images referenced by the
+// cluster, without re-listing on every read. Unlike GetClusterImagesV1/V2
+// it is a long-running service: call Start once and keep it running for
+// the lifetime of the process.
+type ImageIndex struct {
+	factory  informers.SharedInformerFactory
+	informer cache.SharedIndexInformer
+
+	mu   sync.RWMutex
+	refs map[string]map[ObjectRef]struct{}
+
+	subMu       sync.Mutex
+	subscribers []chan ImageEvent
+}
+
+// NewImageIndex builds an ImageIndex on top of clientset. resync is the
+// informer's periodic full resync period (0 disables periodic resync).
+func NewImageIndex(clientset kubernetes.Interface, resync time.Duration) *ImageIndex {
+	factory := informers.NewSharedInformerFactory(clientset, resync)
+
+	idx := &ImageIndex{
+		factory: factory,
+		refs:    make(map[string]map[ObjectRef]struct{}),
+	}
+
+	podInformer := factory.Core().V1().Pods().Informer()
+	podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    idx.onAdd,
+		UpdateFunc: idx.onUpdate,
+		DeleteFunc: idx.onDelete,
+	})
+	idx.informer = podInformer
+
+	return idx
+}
+
+// Start begins the informer's watch loop in the background and returns
+// immediately; the loop stops once ctx is done.
+func (idx *ImageIndex) Start(ctx context.Context) {
+	idx.factory.Start(ctx.Done())
+}
+
+// WaitForSync blocks until the informer's initial List+Watch has completed,
+// or ctx is done.
+func (idx *ImageIndex) WaitForSync(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		idx.factory.WaitForCacheSync(ctx.Done())
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Snapshot returns every image currently referenced by a pod in the cluster.
+func (idx *ImageIndex) Snapshot() []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	images := make([]string, 0, len(idx.refs))
+	for image := range idx.refs {
+		images = append(images, image)
+	}
+	return images
+}
+
+// Subscribe returns a channel of ImageEvent notifying the caller whenever
+// an image starts or stops being referenced. The channel buffers 16
+// events; an event that finds the buffer full is dropped for that
+// subscriber rather than blocking the informer. The channel is closed for
+// good by no one; callers are expected to live for the process lifetime.
+func (idx *ImageIndex) Subscribe() <-chan ImageEvent {
+	ch := make(chan ImageEvent, 16)
+
+	idx.subMu.Lock()
+	idx.subscribers = append(idx.subscribers, ch)
+	idx.subMu.Unlock()
+
+	return ch
+}
+
+func (idx *ImageIndex) publish(event ImageEvent) {
+	idx.subMu.Lock()
+	defer idx.subMu.Unlock()
+
+	for _, ch := range idx.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// slow subscriber, drop rather than block the informer
+		}
+	}
+}
+
+func podObjectRef(pod *v1.Pod) ObjectRef {
+	return ObjectRef{Kind: "Pod", Namespace: pod.Namespace, Name: pod.Name}
+}
+
+func (idx *ImageIndex) onAdd(obj any) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return
+	}
+	idx.addPod(pod)
+}
+
+// onUpdate fires on every pod update, including status-only updates and
+// periodic informer resyncs that touch every pod in the cluster with no
+// image change at all. Diff the old and new image sets and only touch
+// refs that actually changed, instead of unconditionally removing then
+// re-adding the pod's single ref (which would emit a spurious
+// ImageRemoved/ImageAdded pair for every routine update).
+func (idx *ImageIndex) onUpdate(oldObj, newObj any) {
+	oldPod, ok := oldObj.(*v1.Pod)
+	if !ok {
+		return
+	}
+	newPod, ok := newObj.(*v1.Pod)
+	if !ok {
+		return
+	}
+
+	oldImages := toSet(podImages(oldPod))
+	newImages := toSet(podImages(newPod))
+	ref := podObjectRef(newPod)
+
+	for image := range oldImages {
+		if _, stillPresent := newImages[image]; !stillPresent {
+			idx.removeImageRef(image, ref)
+		}
+	}
+	for image := range newImages {
+		if _, wasPresent := oldImages[image]; !wasPresent {
+			idx.addImageRef(image, ref)
+		}
+	}
+}
+
+func (idx *ImageIndex) onDelete(obj any) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			pod, ok = tombstone.Obj.(*v1.Pod)
+			if !ok {
+				return
+			}
+		} else {
+			return
+		}
+	}
+	idx.removePod(pod)
+}
+
+func (idx *ImageIndex) addPod(pod *v1.Pod) {
+	ref := podObjectRef(pod)
+	for _, image := range podImages(pod) {
+		idx.addImageRef(image, ref)
+	}
+}
+
+func (idx *ImageIndex) removePod(pod *v1.Pod) {
+	ref := podObjectRef(pod)
+	for _, image := range podImages(pod) {
+		idx.removeImageRef(image, ref)
+	}
+}
+
+func (idx *ImageIndex) addImageRef(image string, ref ObjectRef) {
+	idx.mu.Lock()
+	set, exists := idx.refs[image]
+	if !exists {
+		set = make(map[ObjectRef]struct{})
+		idx.refs[image] = set
+	}
+	set[ref] = struct{}{}
+	idx.mu.Unlock()
+
+	if !exists {
+		idx.publish(ImageEvent{Type: ImageAdded, Image: image})
+	}
+}
+
+func (idx *ImageIndex) removeImageRef(image string, ref ObjectRef) {
+	idx.mu.Lock()
+	set, exists := idx.refs[image]
+	becameEmpty := false
+	if exists {
+		delete(set, ref)
+		becameEmpty = len(set) == 0
+		if becameEmpty {
+			delete(idx.refs, image)
+		}
+	}
+	idx.mu.Unlock()
+
+	if becameEmpty {
+		idx.publish(ImageEvent{Type: ImageRemoved, Image: image})
+	}
+}
+
+func toSet(images []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(images))
+	for _, image := range images {
+		set[image] = struct{}{}
+	}
+	return set
+}
+
+func podImages(pod *v1.Pod) []string {
+	var images []string
+	for _, c := range pod.Spec.Containers {
+		if c.Image != "" {
+			images = append(images, c.Image)
+		}
+	}
+	for _, c := range pod.Spec.InitContainers {
+		if c.Image != "" {
+			images = append(images, c.Image)
+		}
+	}
+	for _, c := range pod.Spec.EphemeralContainers {
+		if c.Image != "" {
+			images = append(images, c.Image)
+		}
+	}
+	return images
+}
@@ -0,0 +1,38 @@
+package k8s
+
+import (
+	"sync"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestImageIndexConcurrentRefs exercises addImageRef/removeImageRef for
+// refs of the same image concurrently (50 goroutines over 26 pod names, so
+// some goroutines share a ref). Run with -race: before the
+// removeImageRef fix this racily read the shared per-image set after
+// releasing the lock.
+func TestImageIndexConcurrentRefs(t *testing.T) {
+	idx := NewImageIndex(fake.NewSimpleClientset(), 0)
+
+	const n = 50
+	refs := make([]ObjectRef, n)
+	for i := 0; i < n; i++ {
+		refs[i] = ObjectRef{Kind: "Pod", Namespace: "default", Name: string(rune('a' + i%26))}
+	}
+
+	var wg sync.WaitGroup
+	for _, ref := range refs {
+		wg.Add(1)
+		go func(ref ObjectRef) {
+			defer wg.Done()
+			idx.addImageRef("nginx:1.25", ref)
+			idx.removeImageRef("nginx:1.25", ref)
+		}(ref)
+	}
+	wg.Wait()
+
+	if got := idx.Snapshot(); len(got) != 0 {
+		t.Errorf("Snapshot() = %v, want empty once every ref is removed", got)
+	}
+}
@@ -0,0 +1,93 @@
+package k8s
+
+import "testing"
+
+func TestParseReference(t *testing.T) {
+	const digest = "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	cases := []struct {
+		name string
+		ref  string
+		want Image
+	}{
+		{
+			name: "bare name gets docker.io/library and latest",
+			ref:  "nginx",
+			want: Image{Registry: "docker.io", Namespace: "library", Repository: "nginx", Tag: "latest"},
+		},
+		{
+			name: "bare name with tag",
+			ref:  "nginx:1.25",
+			want: Image{Registry: "docker.io", Namespace: "library", Repository: "nginx", Tag: "1.25"},
+		},
+		{
+			name: "explicit namespace with digest, no tag",
+			ref:  "library/nginx@" + digest,
+			want: Image{Registry: "docker.io", Namespace: "library", Repository: "nginx", Digest: digest},
+		},
+		{
+			name: "registry, namespace, tag and digest together",
+			ref:  "gcr.io/proj/img:tag@" + digest,
+			want: Image{Registry: "gcr.io", Namespace: "proj", Repository: "img", Tag: "tag", Digest: digest},
+		},
+		{
+			name: "registry host with port, no namespace",
+			ref:  "localhost:5000/x",
+			want: Image{Registry: "localhost:5000", Namespace: "library", Repository: "x", Tag: "latest"},
+		},
+		{
+			name: "repo:tag is not mistaken for a registry:port",
+			ref:  "team/app:v1.2",
+			want: Image{Registry: "docker.io", Namespace: "team", Repository: "app", Tag: "v1.2"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := ParseReference(c.ref)
+			if err != nil {
+				t.Fatalf("ParseReference(%q) returned error: %v", c.ref, err)
+			}
+			if *got != c.want {
+				t.Errorf("ParseReference(%q) = %+v, want %+v", c.ref, *got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseReferenceEmpty(t *testing.T) {
+	if _, err := ParseReference(""); err == nil {
+		t.Error("ParseReference(\"\") should return an error")
+	}
+}
+
+func TestImageFamiliarRoundTrip(t *testing.T) {
+	const digest = "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	refs := []string{
+		"nginx",
+		"nginx:1.25",
+		"library/nginx@" + digest,
+		"gcr.io/proj/img:tag@" + digest,
+		"localhost:5000/x",
+		"team/app:v1.2",
+	}
+
+	for _, ref := range refs {
+		t.Run(ref, func(t *testing.T) {
+			original, err := ParseReference(ref)
+			if err != nil {
+				t.Fatalf("ParseReference(%q) returned error: %v", ref, err)
+			}
+
+			roundTripped, err := ParseReference(original.Familiar())
+			if err != nil {
+				t.Fatalf("ParseReference(Familiar()) returned error: %v", err)
+			}
+
+			if *roundTripped != *original {
+				t.Errorf("round trip through Familiar() = %+v, want %+v", *roundTripped, *original)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package k8s
+
+import (
+	"context"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ObjectRef points back at the Kubernetes object an image was found on.
+type ObjectRef struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// ImageReference is an image together with everything a registry-cleanup
+// consumer needs to know about it: which secrets can pull it, and which
+// objects in the cluster reference it.
+type ImageReference struct {
+	Image       string
+	PullSecrets []types.NamespacedName
+	Sources     []ObjectRef
+}
+
+// GetClusterImagesAll walks Pods plus every workload controller that can
+// produce pods (Deployments, StatefulSets, DaemonSets, ReplicaSets, Jobs,
+// CronJobs) and unions the images referenced by their containers,
+// initContainers and ephemeralContainers, regardless of whether a pod is
+// currently running.
+func (k *K8SOutCli) GetClusterImagesAll(ctx context.Context) ([]ImageReference, error) {
+	ctx, cancel := context.WithTimeout(ctx, DEFAULT_TIMEOUT*time.Second)
+	defer cancel()
+
+	refs := make(map[string]*ImageReference)
+
+	pods, err := k.clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods.Items {
+		addPodSpec(refs, ObjectRef{Kind: "Pod", Namespace: pod.Namespace, Name: pod.Name}, pod.Namespace, &pod.Spec)
+	}
+
+	deployments, err := k.clientset.AppsV1().Deployments("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range deployments.Items {
+		addPodSpec(refs, ObjectRef{Kind: "Deployment", Namespace: d.Namespace, Name: d.Name}, d.Namespace, &d.Spec.Template.Spec)
+	}
+
+	statefulSets, err := k.clientset.AppsV1().StatefulSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range statefulSets.Items {
+		addPodSpec(refs, ObjectRef{Kind: "StatefulSet", Namespace: s.Namespace, Name: s.Name}, s.Namespace, &s.Spec.Template.Spec)
+	}
+
+	daemonSets, err := k.clientset.AppsV1().DaemonSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, ds := range daemonSets.Items {
+		addPodSpec(refs, ObjectRef{Kind: "DaemonSet", Namespace: ds.Namespace, Name: ds.Name}, ds.Namespace, &ds.Spec.Template.Spec)
+	}
+
+	replicaSets, err := k.clientset.AppsV1().ReplicaSets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, rs := range replicaSets.Items {
+		addPodSpec(refs, ObjectRef{Kind: "ReplicaSet", Namespace: rs.Namespace, Name: rs.Name}, rs.Namespace, &rs.Spec.Template.Spec)
+	}
+
+	jobs, err := k.clientset.BatchV1().Jobs("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, j := range jobs.Items {
+		addPodSpec(refs, ObjectRef{Kind: "Job", Namespace: j.Namespace, Name: j.Name}, j.Namespace, &j.Spec.Template.Spec)
+	}
+
+	cronJobs, err := k.clientset.BatchV1().CronJobs("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, cj := range cronJobs.Items {
+		addPodSpec(refs, ObjectRef{Kind: "CronJob", Namespace: cj.Namespace, Name: cj.Name}, cj.Namespace, &cj.Spec.JobTemplate.Spec.Template.Spec)
+	}
+
+	images := make([]ImageReference, 0, len(refs))
+	for _, ref := range refs {
+		images = append(images, *ref)
+	}
+	return images, nil
+}
+
+// GetClusterImageNamesAll is a thin wrapper around GetClusterImagesAll for
+// callers that only need the bare image names, kept for backward
+// compatibility with the []string-returning GetClusterImages* family.
+func (k *K8SOutCli) GetClusterImageNamesAll(ctx context.Context) ([]string, error) {
+	refs, err := k.GetClusterImagesAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		images = append(images, ref.Image)
+	}
+	return images, nil
+}
+
+// addPodSpec records every container image in spec, plus its
+// imagePullSecrets, against source.
+func addPodSpec(refs map[string]*ImageReference, source ObjectRef, namespace string, spec *v1.PodSpec) {
+	var pullSecrets []types.NamespacedName
+	for _, s := range spec.ImagePullSecrets {
+		pullSecrets = append(pullSecrets, types.NamespacedName{Namespace: namespace, Name: s.Name})
+	}
+
+	addImage := func(image string) {
+		if image == "" {
+			return
+		}
+		ref, exists := refs[image]
+		if !exists {
+			ref = &ImageReference{Image: image}
+			refs[image] = ref
+		}
+		ref.Sources = appendObjectRef(ref.Sources, source)
+		for _, secret := range pullSecrets {
+			ref.PullSecrets = appendNamespacedName(ref.PullSecrets, secret)
+		}
+	}
+
+	for _, c := range spec.Containers {
+		addImage(c.Image)
+	}
+	for _, c := range spec.InitContainers {
+		addImage(c.Image)
+	}
+	for _, c := range spec.EphemeralContainers {
+		addImage(c.Image)
+	}
+}
+
+func appendObjectRef(refs []ObjectRef, ref ObjectRef) []ObjectRef {
+	for _, existing := range refs {
+		if existing == ref {
+			return refs
+		}
+	}
+	return append(refs, ref)
+}
+
+func appendNamespacedName(names []types.NamespacedName, name types.NamespacedName) []types.NamespacedName {
+	for _, existing := range names {
+		if existing == name {
+			return names
+		}
+	}
+	return append(names, name)
+}
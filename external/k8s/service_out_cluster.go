@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/julien-fruteau/go-kubernetes/internal/env"
+	"github.com/julien-fruteau/go-kubernetes/internal/parallel"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -15,11 +16,21 @@ import (
 	"k8s.io/client-go/util/homedir"
 )
 
+// DEFAULT_TIMEOUT bounds how long a single List call against the API
+// server is allowed to take, in seconds.
+const DEFAULT_TIMEOUT = 30
+
 type K8SOutCli struct {
 	ctx       context.Context
 	clientset *kubernetes.Clientset
 }
 
+// Clientset exposes the underlying client-go clientset, for callers that
+// need to build their own informers or watches on top of it.
+func (k *K8SOutCli) Clientset() kubernetes.Interface {
+	return k.clientset
+}
+
 func getKubeConfig() (string, error) {
 	// get env KUBE_CONFIG or default homedir kube config
 
@@ -168,39 +179,41 @@ func getImagesV2(podChan <-chan v1.Pod) []string {
 	return images
 }
 
+// GetImagesV3 processes containers from all pods concurrently through a
+// bounded worker pool (see internal/parallel), instead of spawning one
+// goroutine per pod: on a large cluster that used to create more
+// goroutines than the single result channel could ever drain.
 func (k *K8SOutCli) GetImagesV3(pods *v1.PodList) []string {
-	// Use a map for O(1) lookups to avoid duplicates
 	imageSet := make(map[string]struct{})
 
-	// Process containers from all pods concurrently
-	ch := make(chan string)
-	var wg sync.WaitGroup
+	limit := parallel.DefaultLimit()
+	pool := parallel.New(limit)
+	ch := make(chan string, limit)
+
+	var collect sync.WaitGroup
+	collect.Add(1)
+	go func() {
+		defer collect.Done()
+		for image := range ch {
+			imageSet[image] = struct{}{}
+		}
+	}()
 
-	// Launch goroutine for each pod
 	for i := range pods.Items {
-		wg.Add(1)
-		go func(pod *v1.Pod) {
-			defer wg.Done()
+		pod := &pods.Items[i]
+		pool.Go(func() {
 			for _, container := range pod.Spec.Containers {
 				if container.Image != "" {
 					ch <- container.Image
 				}
 			}
-		}(&pods.Items[i])
+		})
 	}
 
-	// Close channel when all goroutines complete
-	go func() {
-		wg.Wait()
-		close(ch)
-	}()
-
-	// Collect unique images
-	for image := range ch {
-		imageSet[image] = struct{}{}
-	}
+	pool.Wait()
+	close(ch)
+	collect.Wait()
 
-	// Convert map keys to slice
 	images := make([]string, 0, len(imageSet))
 	for image := range imageSet {
 		images = append(images, image)
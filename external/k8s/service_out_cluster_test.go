@@ -0,0 +1,66 @@
+package k8s
+
+import (
+	"fmt"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// syntheticPodList builds a PodList of n pods, each with a handful of
+// containers drawn from a small pool of images so dedup has real work to do.
+func syntheticPodList(n int) *v1.PodList {
+	images := []string{
+		"nginx:1.25",
+		"gcr.io/proj/app:v1",
+		"redis:7",
+		"myregistry.example.com/team/worker:latest",
+	}
+
+	pods := make([]v1.Pod, n)
+	for i := 0; i < n; i++ {
+		containers := make([]v1.Container, len(images))
+		for j, image := range images {
+			containers[j] = v1.Container{Image: image}
+		}
+		pods[i] = v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod-%d", i)},
+			Spec:       v1.PodSpec{Containers: containers},
+		}
+	}
+
+	return &v1.PodList{Items: pods}
+}
+
+func BenchmarkGetImages_V1(b *testing.B) {
+	pods := syntheticPodList(5000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		getImagesV1(pods)
+	}
+}
+
+func BenchmarkGetImages_V2(b *testing.B) {
+	pods := syntheticPodList(5000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		podChan := make(chan v1.Pod)
+		go func() {
+			defer close(podChan)
+			for _, pod := range pods.Items {
+				podChan <- pod
+			}
+		}()
+		getImagesV2(podChan)
+	}
+}
+
+func BenchmarkGetImages_V3(b *testing.B) {
+	pods := syntheticPodList(5000)
+	k := &K8SOutCli{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		k.GetImagesV3(pods)
+	}
+}
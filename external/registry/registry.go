@@ -0,0 +1,560 @@
+// Package registry implements a pruner for OCI/Docker v2 registries
+// (Harbor, GHCR, ECR, GAR, or any distribution-compatible registry): it
+// lists what the registry holds, diffs it against the set of images a
+// cluster actually references, and deletes the rest through the
+// Distribution HTTP API. Authentication supports a statically configured
+// bearer token or basic auth, the standard Distribution
+// WWW-Authenticate/token-exchange challenge used by Docker Hub, GHCR and
+// Quay, and a CredentialProvider hook for cloud credential helpers
+// (ECR, GAR, ...) that mint short-lived basic-auth credentials.
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/julien-fruteau/go-kubernetes/external/k8s"
+)
+
+const (
+	// DEFAULT_TIMEOUT is the per-request timeout against the registry API.
+	DEFAULT_TIMEOUT = 30
+
+	acceptManifestV2  = "application/vnd.docker.distribution.manifest.v2+json"
+	acceptManifestOCI = "application/vnd.oci.image.manifest.v1+json"
+)
+
+// Config controls how a Client talks to and prunes a registry.
+type Config struct {
+	// BaseURL is the registry root, e.g. "https://registry.example.com".
+	BaseURL string
+
+	// Auth: at most one of these should be set. BearerToken and
+	// Username/Password are tried on the first request to each endpoint;
+	// if the registry instead replies 401 with a WWW-Authenticate: Bearer
+	// challenge (Docker Hub, GHCR, Quay, ...), the Client exchanges it for
+	// a short-lived token via the challenge's realm and retries.
+	BearerToken string
+	Username    string
+	Password    string
+
+	// Credentials, if set, is consulted instead of Username/Password both
+	// for direct basic auth and for the token-exchange challenge — e.g. to
+	// shell out to a cloud credential helper (docker-credential-ecr-login,
+	// gcloud, ...) that mints short-lived registry credentials.
+	Credentials CredentialProvider
+
+	// DryRun, when true, only reports what would be deleted.
+	DryRun bool
+
+	// KeepLast keeps the N most recently created tags per repository
+	// regardless of cluster references. 0 disables this rule.
+	KeepLast int
+
+	// KeepNewerThan keeps tags created more recently than this duration
+	// regardless of cluster references. 0 disables this rule.
+	KeepNewerThan time.Duration
+
+	// Allow, if non-empty, restricts pruning to these repositories only.
+	Allow []string
+
+	// Deny excludes these repositories from pruning even if they also
+	// match Allow.
+	Deny []string
+}
+
+// CredentialProvider resolves registry credentials at request time,
+// e.g. by shelling out to a cloud-specific credential helper.
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (username, password string, err error)
+}
+
+// Client prunes a single registry according to a Config.
+type Client struct {
+	cfg  Config
+	http *http.Client
+}
+
+// NewClient builds a Client for the registry described by cfg.
+func NewClient(cfg Config) *Client {
+	return &Client{
+		cfg:  cfg,
+		http: &http.Client{Timeout: DEFAULT_TIMEOUT * time.Second},
+	}
+}
+
+// TagInfo describes a single tag of a repository as seen in the registry.
+type TagInfo struct {
+	Repository string
+	Tag        string
+	Digest     string
+	Created    time.Time
+}
+
+// ManifestRef uniquely identifies a manifest to delete.
+type ManifestRef struct {
+	Repository string
+	Digest     string
+	Reason     string
+}
+
+// Plan is the result of diffing the registry against the cluster's
+// referenced images: Delete holds manifests that would be/were removed,
+// Keep holds manifests that were spared and why.
+type Plan struct {
+	Delete []ManifestRef
+	Keep   []ManifestRef
+}
+
+// credentials resolves basic-auth credentials, preferring a configured
+// CredentialProvider over the static Username/Password.
+func (c *Client) credentials(ctx context.Context) (username, password string, err error) {
+	if c.cfg.Credentials != nil {
+		return c.cfg.Credentials.Credentials(ctx)
+	}
+	return c.cfg.Username, c.cfg.Password, nil
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, accept string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.cfg.BaseURL, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	switch {
+	case c.cfg.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+c.cfg.BearerToken)
+	default:
+		username, password, err := c.credentials(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+	}
+
+	return req, nil
+}
+
+// do performs the request and, if the registry challenges it with a
+// WWW-Authenticate: Bearer header (the standard Distribution token-exchange
+// flow used by Docker Hub, GHCR, Quay, ...), exchanges the challenge for a
+// token and retries once with it.
+func (c *Client) do(ctx context.Context, method, path, accept string) (*http.Response, error) {
+	req, err := c.newRequest(ctx, method, path, accept)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+
+	token, err := c.exchangeToken(ctx, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	retry, err := c.newRequest(ctx, method, path, accept)
+	if err != nil {
+		return nil, err
+	}
+	retry.Header.Set("Authorization", "Bearer "+token)
+
+	return c.http.Do(retry)
+}
+
+// exchangeToken implements the Distribution token-exchange flow: parse the
+// Bearer challenge's realm/service/scope, request a token from the realm
+// (authenticating with the configured credentials, if any), and return it.
+func (c *Client) exchangeToken(ctx context.Context, challenge string) (string, error) {
+	params, ok := parseBearerChallenge(challenge)
+	if !ok {
+		return "", fmt.Errorf("registry: unsupported auth challenge: %q", challenge)
+	}
+
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("registry: auth challenge missing realm: %q", challenge)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm, nil)
+	if err != nil {
+		return "", err
+	}
+
+	query := req.URL.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	if scope := params["scope"]; scope != "" {
+		query.Set("scope", scope)
+	}
+	req.URL.RawQuery = query.Encode()
+
+	username, password, err := c.credentials(ctx)
+	if err != nil {
+		return "", err
+	}
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("registry: token exchange against %s failed: %s: %s", realm, resp.Status, string(body))
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+// parseBearerChallenge parses a "Bearer realm=\"...\",service=\"...\",scope=\"...\""
+// WWW-Authenticate header into its key/value parameters.
+func parseBearerChallenge(header string) (map[string]string, bool) {
+	const prefix = "Bearer "
+	if !strings.HasPrefix(header, prefix) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for _, part := range splitChallengeParams(strings.TrimPrefix(header, prefix)) {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		params[kv[0]] = strings.Trim(kv[1], `"`)
+	}
+	return params, true
+}
+
+// splitChallengeParams splits a WWW-Authenticate parameter list on commas
+// that fall outside quoted values, since a quoted scope can itself contain
+// commas (e.g. scope="repository:samalba/my-app:pull,push").
+func splitChallengeParams(s string) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for i, r := range s {
+		switch r {
+		case '"':
+			inQuotes = !inQuotes
+		case ',':
+			if !inQuotes {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// ListRepositories returns every repository name in the registry's catalog.
+func (c *Client) ListRepositories(ctx context.Context) ([]string, error) {
+	var repos []string
+	last := ""
+
+	for {
+		path := "/v2/_catalog?n=100"
+		if last != "" {
+			path += "&last=" + last
+		}
+
+		resp, err := c.do(ctx, http.MethodGet, path, "")
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Repositories []string `json:"repositories"`
+		}
+		err = decodeAndClose(resp, &page)
+		if err != nil {
+			return nil, err
+		}
+		if len(page.Repositories) == 0 {
+			break
+		}
+
+		repos = append(repos, page.Repositories...)
+		last = page.Repositories[len(page.Repositories)-1]
+	}
+
+	return repos, nil
+}
+
+// ListTags returns every tag of a repository.
+func (c *Client) ListTags(ctx context.Context, repo string) ([]string, error) {
+	resp, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/v2/%s/tags/list", repo), "")
+	if err != nil {
+		return nil, err
+	}
+	var body struct {
+		Tags []string `json:"tags"`
+	}
+	if err := decodeAndClose(resp, &body); err != nil {
+		return nil, err
+	}
+	return body.Tags, nil
+}
+
+// TagInfo fetches the manifest digest and creation time for a single tag.
+func (c *Client) TagInfo(ctx context.Context, repo, tag string) (TagInfo, error) {
+	info := TagInfo{Repository: repo, Tag: tag}
+
+	resp, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/v2/%s/manifests/%s", repo, tag), acceptManifestV2+", "+acceptManifestOCI)
+	if err != nil {
+		return info, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return info, fmt.Errorf("registry: get manifest %s:%s: unexpected status %s", repo, tag, resp.Status)
+	}
+
+	info.Digest = resp.Header.Get("Docker-Content-Digest")
+
+	var manifest struct {
+		Config struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+		return info, err
+	}
+
+	created, err := c.configCreated(ctx, repo, manifest.Config.Digest)
+	if err == nil {
+		info.Created = created
+	}
+
+	return info, nil
+}
+
+// configCreated fetches the image config blob and extracts its "created" timestamp.
+func (c *Client) configCreated(ctx context.Context, repo, digest string) (time.Time, error) {
+	if digest == "" {
+		return time.Time{}, errors.New("registry: empty config digest")
+	}
+
+	resp, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/v2/%s/blobs/%s", repo, digest), "")
+	if err != nil {
+		return time.Time{}, err
+	}
+	var config struct {
+		Created time.Time `json:"created"`
+	}
+	if err := decodeAndClose(resp, &config); err != nil {
+		return time.Time{}, err
+	}
+
+	return config.Created, nil
+}
+
+// DeleteManifest removes a manifest by digest from a repository.
+func (c *Client) DeleteManifest(ctx context.Context, repo, digest string) error {
+	resp, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/v2/%s/manifests/%s", repo, digest), "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("registry: delete manifest %s@%s: unexpected status %s", repo, digest, resp.Status)
+	}
+	return nil
+}
+
+// allowed reports whether repo should be considered for pruning given the
+// configured allow/deny lists.
+func (c *Client) allowed(repo string) bool {
+	if len(c.cfg.Allow) > 0 && !contains(c.cfg.Allow, repo) {
+		return false
+	}
+	return !contains(c.cfg.Deny, repo)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Plan computes which manifests would be deleted to prune every repository
+// down to the images referenced by referenced (full image references, as
+// produced by k8s.K8SOutCli.GetClusterImages*, e.g. "nginx:1.25" or
+// "myregistry.example.com/team/app:v1@sha256:..."). referenced is matched
+// against the registry's catalog-relative repository names (which never
+// include the registry host, since that's implied by Config.BaseURL) by
+// parsing each referenced image through k8s.ParseReference and comparing
+// its namespace/repository path, or its bare repository name, with the
+// catalog name as the registry reports it, rather than comparing the raw
+// strings directly.
+func (c *Client) Plan(ctx context.Context, referenced map[string]struct{}) (*Plan, error) {
+	plan := &Plan{}
+	cutoff := time.Time{}
+	if c.cfg.KeepNewerThan > 0 {
+		cutoff = time.Now().Add(-c.cfg.KeepNewerThan)
+	}
+
+	referencedKeys := normalizeReferenced(referenced)
+
+	repos, err := c.ListRepositories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, repo := range repos {
+		if !c.allowed(repo) {
+			continue
+		}
+
+		tags, err := c.ListTags(ctx, repo)
+		if err != nil {
+			return nil, fmt.Errorf("registry: list tags for %s: %w", repo, err)
+		}
+
+		infos := make([]TagInfo, 0, len(tags))
+		for _, tag := range tags {
+			info, err := c.TagInfo(ctx, repo, tag)
+			if err != nil {
+				return nil, fmt.Errorf("registry: inspect %s:%s: %w", repo, tag, err)
+			}
+			infos = append(infos, info)
+		}
+
+		sort.Slice(infos, func(i, j int) bool { return infos[i].Created.After(infos[j].Created) })
+
+		// Deleting a manifest removes every tag pointing at it, so a digest
+		// shared with a kept tag is never deleted.
+		kept := make(map[string]struct{})
+		var unreferenced []ManifestRef
+		for i, info := range infos {
+			ref := ManifestRef{Repository: repo, Digest: info.Digest}
+
+			switch {
+			case isReferenced(referencedKeys, repo, info):
+				ref.Reason = "referenced by cluster"
+			case c.cfg.KeepLast > 0 && i < c.cfg.KeepLast:
+				ref.Reason = "within keep-last window"
+			case !cutoff.IsZero() && info.Created.After(cutoff):
+				ref.Reason = "within keep-newer-than window"
+			default:
+				ref.Reason = "unreferenced"
+				unreferenced = append(unreferenced, ref)
+				continue
+			}
+
+			kept[info.Digest] = struct{}{}
+			plan.Keep = append(plan.Keep, ref)
+		}
+		for _, ref := range unreferenced {
+			if _, ok := kept[ref.Digest]; !ok {
+				plan.Delete = append(plan.Delete, ref)
+			}
+		}
+	}
+
+	return plan, nil
+}
+
+// normalizeReferenced parses each raw cluster image reference and builds
+// the set of catalog-relative keys it could match in this registry. Both
+// "namespace/repository:tag" and the bare "repository:tag" (and their
+// @digest equivalents) are added, since ParseReference defaults an
+// implicit namespace to "library" the way Docker Hub expects, but a
+// private registry's catalog generally reports such repositories without
+// that prefix at all.
+func normalizeReferenced(referenced map[string]struct{}) map[string]struct{} {
+	keys := make(map[string]struct{}, len(referenced)*2)
+	for image := range referenced {
+		ref, err := k8s.ParseReference(image)
+		if err != nil {
+			continue
+		}
+
+		add := func(suffix string) {
+			keys[ref.Namespace+"/"+ref.Repository+suffix] = struct{}{}
+			keys[ref.Repository+suffix] = struct{}{}
+		}
+		if ref.Tag != "" {
+			add(":" + ref.Tag)
+		}
+		if ref.Digest != "" {
+			add("@" + ref.Digest)
+		}
+	}
+	return keys
+}
+
+func isReferenced(referencedKeys map[string]struct{}, repo string, info TagInfo) bool {
+	_, byTag := referencedKeys[repo+":"+info.Tag]
+	_, byDigest := referencedKeys[repo+"@"+info.Digest]
+	return byTag || byDigest
+}
+
+// Execute deletes every manifest in plan.Delete, unless the Client is
+// configured for DryRun, in which case it only reports what it would do.
+func (c *Client) Execute(ctx context.Context, plan *Plan) error {
+	for _, ref := range plan.Delete {
+		if c.cfg.DryRun {
+			fmt.Printf("dry-run: would delete %s@%s (%s)\n", ref.Repository, ref.Digest, ref.Reason)
+			continue
+		}
+
+		if err := c.DeleteManifest(ctx, ref.Repository, ref.Digest); err != nil {
+			return err
+		}
+		fmt.Printf("deleted %s@%s (%s)\n", ref.Repository, ref.Digest, ref.Reason)
+	}
+
+	return nil
+}
+
+func decodeAndClose(resp *http.Response, v any) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("registry: unexpected status %s: %s", resp.Status, string(body))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
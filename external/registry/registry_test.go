@@ -0,0 +1,100 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsReferenced(t *testing.T) {
+	referenced := map[string]struct{}{
+		"nginx:1.25":                         {}, // short name, no registry host or namespace
+		"myregistry.example.com/team/app:v1": {}, // private registry, host implied by Config.BaseURL
+		"myregistry.example.com/single:v2":   {}, // private registry, single-segment repo path
+	}
+	keys := normalizeReferenced(referenced)
+
+	cases := []struct {
+		name string
+		repo string
+		info TagInfo
+		want bool
+	}{
+		{
+			name: "docker hub short name matches its library/ catalog path",
+			repo: "library/nginx",
+			info: TagInfo{Tag: "1.25"},
+			want: true,
+		},
+		{
+			name: "same repository, unreferenced tag",
+			repo: "library/nginx",
+			info: TagInfo{Tag: "1.26"},
+			want: false,
+		},
+		{
+			name: "registry host is stripped before matching",
+			repo: "team/app",
+			info: TagInfo{Tag: "v1"},
+			want: true,
+		},
+		{
+			name: "unreferenced tag and digest do not match",
+			repo: "team/app",
+			info: TagInfo{Tag: "unrelated", Digest: "sha256:abc"},
+			want: false,
+		},
+		{
+			name: "single-segment repo on a private registry matches without a library/ prefix",
+			repo: "single",
+			info: TagInfo{Tag: "v2"},
+			want: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := isReferenced(keys, c.repo, c.info)
+			if got != c.want {
+				t.Errorf("isReferenced(%q, %+v) = %v, want %v", c.repo, c.info, got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseBearerChallenge(t *testing.T) {
+	header := `Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"`
+
+	params, ok := parseBearerChallenge(header)
+	if !ok {
+		t.Fatalf("parseBearerChallenge(%q) returned ok=false", header)
+	}
+
+	want := map[string]string{
+		"realm":   "https://auth.docker.io/token",
+		"service": "registry.docker.io",
+		"scope":   "repository:library/nginx:pull",
+	}
+	if !reflect.DeepEqual(params, want) {
+		t.Errorf("parseBearerChallenge(%q) = %v, want %v", header, params, want)
+	}
+}
+
+func TestParseBearerChallengeNotBearer(t *testing.T) {
+	if _, ok := parseBearerChallenge(`Basic realm="registry"`); ok {
+		t.Error("parseBearerChallenge should reject non-Bearer challenges")
+	}
+}
+
+func TestParseBearerChallengeCommaInScope(t *testing.T) {
+	header := `Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:samalba/my-app:pull,push"`
+
+	params, ok := parseBearerChallenge(header)
+	if !ok {
+		t.Fatalf("parseBearerChallenge(%q) returned ok=false", header)
+	}
+
+	want := "repository:samalba/my-app:pull,push"
+	if params["scope"] != want {
+		t.Errorf("scope = %q, want %q", params["scope"], want)
+	}
+}
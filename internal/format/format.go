@@ -0,0 +1,198 @@
+// Package format renders image listings in the output format requested on
+// the CLI (json, yaml, table, or a user-supplied go-template).
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/julien-fruteau/go-kubernetes/external/k8s"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Entry is one image in the listing. Namespaces and PullSecrets are only
+// populated when the caller has the richer k8s.ImageReference data
+// available; formatters degrade gracefully when they're empty.
+type Entry struct {
+	Repository  string   `json:"repository" yaml:"repository"`
+	Tag         string   `json:"tag,omitempty" yaml:"tag,omitempty"`
+	Digest      string   `json:"digest,omitempty" yaml:"digest,omitempty"`
+	Namespaces  []string `json:"namespaces,omitempty" yaml:"namespaces,omitempty"`
+	PullSecrets []string `json:"pullSecrets,omitempty" yaml:"pullSecrets,omitempty"`
+}
+
+// Formatter renders a set of entries to w.
+type Formatter interface {
+	Format(w io.Writer, entries []Entry) error
+}
+
+// EntriesFromImages builds the plain entries produced by the []string
+// GetClusterImages* APIs: repository, tag and digest, with no namespace
+// or pull-secret information. Images are parsed with k8s.ParseReference
+// so a registry port, a digest-only reference, or a combined tag+digest
+// reference all split correctly, unlike a naive split on the first ":".
+func EntriesFromImages(images []string) []Entry {
+	entries := make([]Entry, 0, len(images))
+	for _, image := range images {
+		entries = append(entries, entryFromImage(image))
+	}
+	return entries
+}
+
+// EntriesFromImageReferences builds entries from the richer
+// k8s.ImageReference data GetClusterImagesAll produces, populating
+// Namespaces and PullSecrets from ImageReference.Sources/PullSecrets so
+// the table formatter's corresponding columns aren't always empty.
+func EntriesFromImageReferences(refs []k8s.ImageReference) []Entry {
+	entries := make([]Entry, 0, len(refs))
+	for _, ref := range refs {
+		entry := entryFromImage(ref.Image)
+		entry.Namespaces = sourceNamespaces(ref.Sources)
+		entry.PullSecrets = pullSecretNames(ref.PullSecrets)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// entryFromImage parses image with k8s.ParseReference and builds the
+// Repository/Tag/Digest fields shared by both Entries constructors.
+// Repository includes the registry host whenever it isn't the Docker Hub
+// default, so images with the same namespace/repository pulled from
+// different registries don't collapse into one indistinguishable entry.
+func entryFromImage(image string) Entry {
+	ref, err := k8s.ParseReference(image)
+	if err != nil {
+		return Entry{Repository: image}
+	}
+
+	repository := ref.Namespace + "/" + ref.Repository
+	if ref.Registry != "docker.io" {
+		repository = ref.Registry + "/" + repository
+	}
+	return Entry{
+		Repository: repository,
+		Tag:        ref.Tag,
+		Digest:     ref.Digest,
+	}
+}
+
+// sourceNamespaces returns the deduped, sorted set of namespaces an image's
+// sources belong to.
+func sourceNamespaces(sources []k8s.ObjectRef) []string {
+	seen := make(map[string]struct{}, len(sources))
+	var namespaces []string
+	for _, source := range sources {
+		if _, ok := seen[source.Namespace]; ok {
+			continue
+		}
+		seen[source.Namespace] = struct{}{}
+		namespaces = append(namespaces, source.Namespace)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// pullSecretNames returns the pull secrets as sorted "namespace/name" strings.
+func pullSecretNames(secrets []types.NamespacedName) []string {
+	names := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		names = append(names, secret.Namespace+"/"+secret.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New returns the Formatter registered under name. tmpl is only used by
+// the "template" format. An unknown name returns an error listing the
+// formats that are supported.
+func New(name, tmpl string) (Formatter, error) {
+	switch name {
+	case "json":
+		return jsonFormatter{}, nil
+	case "yaml":
+		return yamlFormatter{}, nil
+	case "table":
+		return tableFormatter{}, nil
+	case "template":
+		return newTemplateFormatter(tmpl)
+	default:
+		return nil, fmt.Errorf("format: unsupported output format %q, supported formats are: json, yaml, table, template", name)
+	}
+}
+
+type jsonFormatter struct{}
+
+func (jsonFormatter) Format(w io.Writer, entries []Entry) error {
+	enc := json.NewEncoder(w)
+	return enc.Encode(entries)
+}
+
+type yamlFormatter struct{}
+
+func (yamlFormatter) Format(w io.Writer, entries []Entry) error {
+	enc := yaml.NewEncoder(w)
+	defer enc.Close()
+	return enc.Encode(entries)
+}
+
+type tableFormatter struct{}
+
+func (tableFormatter) Format(w io.Writer, entries []Entry) error {
+	tw := tabwriter.NewWriter(w, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPOSITORY\tTAG\tDIGEST\tNAMESPACES\tPULLSECRETS")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.Repository, e.Tag, e.Digest, strings.Join(e.Namespaces, ","), strings.Join(e.PullSecrets, ","))
+	}
+	return tw.Flush()
+}
+
+type templateFormatter struct {
+	tmpl *template.Template
+}
+
+// funcMap offers a handful of Sprig-like string helpers commonly used in
+// --template expressions, without pulling in the full Sprig dependency.
+var funcMap = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	"join":  strings.Join,
+}
+
+func newTemplateFormatter(tmpl string) (Formatter, error) {
+	if tmpl == "" {
+		return nil, fmt.Errorf("format: --template is required for the template output format")
+	}
+
+	t, err := template.New("output").Funcs(funcMap).Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("format: invalid template: %w", err)
+	}
+	return templateFormatter{tmpl: t}, nil
+}
+
+func (f templateFormatter) Format(w io.Writer, entries []Entry) error {
+	for _, e := range entries {
+		if err := f.tmpl.Execute(w, e); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// SortEntries orders entries by repository then tag, for deterministic output.
+func SortEntries(entries []Entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Repository != entries[j].Repository {
+			return entries[i].Repository < entries[j].Repository
+		}
+		return entries[i].Tag < entries[j].Tag
+	})
+}
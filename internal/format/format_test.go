@@ -0,0 +1,139 @@
+package format
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/julien-fruteau/go-kubernetes/external/k8s"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestEntriesFromImages(t *testing.T) {
+	entries := EntriesFromImages([]string{"nginx:1.25", "gcr.io/proj/app:v1"})
+
+	want := []Entry{
+		{Repository: "library/nginx", Tag: "1.25"},
+		{Repository: "gcr.io/proj/app", Tag: "v1"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("EntriesFromImages() = %+v, want %+v", entries, want)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(entries[i], want[i]) {
+			t.Errorf("entries[%d] = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+}
+
+func TestEntriesFromImageReferences(t *testing.T) {
+	refs := []k8s.ImageReference{
+		{
+			Image: "nginx:1.25",
+			Sources: []k8s.ObjectRef{
+				{Kind: "Pod", Namespace: "prod", Name: "web-1"},
+				{Kind: "Pod", Namespace: "staging", Name: "web-1"},
+				{Kind: "Pod", Namespace: "prod", Name: "web-2"}, // duplicate namespace
+			},
+			PullSecrets: []types.NamespacedName{
+				{Namespace: "prod", Name: "regcred"},
+			},
+		},
+	}
+
+	entries := EntriesFromImageReferences(refs)
+	if len(entries) != 1 {
+		t.Fatalf("EntriesFromImageReferences() = %+v, want 1 entry", entries)
+	}
+
+	entry := entries[0]
+	if entry.Repository != "library/nginx" || entry.Tag != "1.25" {
+		t.Errorf("entry = %+v, want Repository=library/nginx Tag=1.25", entry)
+	}
+
+	wantNamespaces := []string{"prod", "staging"}
+	if !equalStrings(entry.Namespaces, wantNamespaces) {
+		t.Errorf("Namespaces = %v, want %v", entry.Namespaces, wantNamespaces)
+	}
+
+	wantPullSecrets := []string{"prod/regcred"}
+	if !equalStrings(entry.PullSecrets, wantPullSecrets) {
+		t.Errorf("PullSecrets = %v, want %v", entry.PullSecrets, wantPullSecrets)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestNewUnknownFormat(t *testing.T) {
+	if _, err := New("xml", ""); err == nil {
+		t.Error("New(\"xml\", \"\") should return an error")
+	}
+}
+
+func TestNewTemplateRequiresTemplate(t *testing.T) {
+	if _, err := New("template", ""); err == nil {
+		t.Error("New(\"template\", \"\") should return an error")
+	}
+}
+
+func TestTableFormatterOutput(t *testing.T) {
+	entries := []Entry{
+		{Repository: "library/nginx", Tag: "1.25", Namespaces: []string{"prod"}},
+	}
+
+	var buf bytes.Buffer
+	if err := (tableFormatter{}).Format(&buf, entries); err != nil {
+		t.Fatalf("Format() returned error: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "REPOSITORY") || !strings.Contains(out, "library/nginx") || !strings.Contains(out, "prod") {
+		t.Errorf("table output missing expected columns: %q", out)
+	}
+}
+
+func TestTemplateFormatterOutput(t *testing.T) {
+	formatter, err := New("template", "{{.Repository | upper}}:{{.Tag}}")
+	if err != nil {
+		t.Fatalf("New() returned error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	entries := []Entry{{Repository: "library/nginx", Tag: "1.25"}}
+	if err := formatter.Format(&buf, entries); err != nil {
+		t.Fatalf("Format() returned error: %v", err)
+	}
+
+	want := "LIBRARY/NGINX:1.25\n"
+	if buf.String() != want {
+		t.Errorf("Format() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestSortEntries(t *testing.T) {
+	entries := []Entry{
+		{Repository: "b/app", Tag: "v2"},
+		{Repository: "a/app", Tag: "v1"},
+		{Repository: "a/app", Tag: "v0"},
+	}
+	SortEntries(entries)
+
+	want := []string{"a/app:v0", "a/app:v1", "b/app:v2"}
+	for i, e := range entries {
+		got := e.Repository + ":" + e.Tag
+		if got != want[i] {
+			t.Errorf("entries[%d] = %s, want %s", i, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Package parallel provides a small bounded worker pool, so fan-out code
+// doesn't spawn one goroutine per item regardless of how many items there are.
+package parallel
+
+import (
+	"runtime"
+	"strconv"
+	"sync"
+
+	"github.com/julien-fruteau/go-kubernetes/internal/env"
+)
+
+// envWorkers overrides DefaultLimit when set to a positive integer.
+const envWorkers = "K8S_IMAGE_WORKERS"
+
+// DefaultLimit returns runtime.NumCPU()*2, overridable via the
+// K8S_IMAGE_WORKERS environment variable.
+func DefaultLimit() int {
+	if v := env.GetEnvOrDefault(envWorkers, ""); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return runtime.NumCPU() * 2
+}
+
+// Pool runs jobs with at most limit running concurrently.
+type Pool struct {
+	sem chan struct{}
+	wg  sync.WaitGroup
+}
+
+// New builds a Pool capped at limit concurrent jobs. limit <= 0 falls back
+// to DefaultLimit().
+func New(limit int) *Pool {
+	if limit <= 0 {
+		limit = DefaultLimit()
+	}
+	return &Pool{sem: make(chan struct{}, limit)}
+}
+
+// Go submits fn to run once a slot is free, blocking until one is.
+func (p *Pool) Go(fn func()) {
+	p.wg.Add(1)
+	p.sem <- struct{}{}
+	go func() {
+		defer p.wg.Done()
+		defer func() { <-p.sem }()
+		fn()
+	}()
+}
+
+// Wait blocks until every submitted job has completed.
+func (p *Pool) Wait() {
+	p.wg.Wait()
+}